@@ -0,0 +1,201 @@
+/*
+* Copyright 2021 ICON Foundation
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package icon
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SenderPolicy selects how the sender picks the next pending relay message
+// to ship, and whether it fee-bumps messages that stall in the mempool.
+type SenderPolicy string
+
+const (
+	// SenderPolicyFIFO ships relay messages in the order Relay was
+	// called. This is the default and was the only behavior before
+	// SenderPolicy existed.
+	SenderPolicyFIFO SenderPolicy = "fifo"
+	// SenderPolicyPriority reorders pending messages by priority
+	// (oldest RxSeq first), falling back to enqueue order among ties.
+	SenderPolicyPriority SenderPolicy = "priority"
+	// SenderPolicyFeeBump behaves like SenderPolicyPriority and, in
+	// addition, resubmits a message with a higher StepLimit once it has
+	// sat in the mempool past DefaultGetRelayResultInterval*DefaultStuckAfter.
+	SenderPolicyFeeBump SenderPolicy = "feebump"
+)
+
+// DefaultStuckAfter is how many DefaultGetRelayResultInterval ticks a
+// transaction may sit unconfirmed before SenderPolicyFeeBump resubmits it.
+const DefaultStuckAfter = 10
+
+// prioritized is implemented by relay messages that can report a relative
+// send priority; lower values are relayed first. link.relayMessage
+// implements it via Priority(), returning its BMCLinkStatus().RxSeq so the
+// oldest pending sequence is prioritized.
+type prioritized interface {
+	Priority() int64
+}
+
+type relayMessageTx struct {
+	id         int
+	txHash     []byte
+	priority   int64
+	enqueued   time.Time
+	stepLimit  int64
+	resends    int
+	generation int
+}
+
+// queueHeap implements container/heap.Interface, ordering by (priority,
+// enqueued) ascending so the most urgent pending message sorts first.
+type queueHeap []*relayMessageTx
+
+func (h queueHeap) Len() int { return len(h) }
+
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].enqueued.Before(h[j].enqueued)
+}
+
+func (h queueHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *queueHeap) Push(x interface{}) {
+	*h = append(*h, x.(*relayMessageTx))
+}
+
+func (h *queueHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	tx := old[n-1]
+	*h = old[:n-1]
+	return tx
+}
+
+// Queue holds the relay message transactions a transport is waiting on a
+// result for. In SenderPolicyFIFO it is the original plain slice processed
+// in append order; in SenderPolicyPriority/FeeBump it is a min-heap so the
+// highest-priority pending message is the one fee-bump/resend logic acts
+// on first.
+type Queue struct {
+	policy SenderPolicy
+	fifo   []*relayMessageTx
+	heap   queueHeap
+}
+
+func NewQueue(policy SenderPolicy) *Queue {
+	if policy == "" {
+		policy = SenderPolicyFIFO
+	}
+	return &Queue{policy: policy}
+}
+
+type txOption func(*relayMessageTx)
+
+func withPriority(p int64) txOption { return func(tx *relayMessageTx) { tx.priority = p } }
+
+func withStepLimit(s int64) txOption { return func(tx *relayMessageTx) { tx.stepLimit = s } }
+
+func (q *Queue) enqueue(id int, txHash []byte, opts ...txOption) error {
+	if MaxQueueSize <= q.len() {
+		return fmt.Errorf("queue full")
+	}
+	tx := &relayMessageTx{id: id, txHash: txHash, enqueued: time.Now()}
+	for _, opt := range opts {
+		opt(tx)
+	}
+	if q.policy == SenderPolicyFIFO {
+		q.fifo = append(q.fifo, tx)
+	} else {
+		heap.Push(&q.heap, tx)
+	}
+	return nil
+}
+
+func (q *Queue) dequeue(id int) {
+	if q.policy == SenderPolicyFIFO {
+		for i, tx := range q.fifo {
+			if tx.id == id {
+				q.fifo = append(q.fifo[:i], q.fifo[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+	for i, tx := range q.heap {
+		if tx.id == id {
+			heap.Remove(&q.heap, i)
+			break
+		}
+	}
+}
+
+// txHash returns the tx hash of the pending transaction for id, if any.
+func (q *Queue) txHash(id int) ([]byte, bool) {
+	tx, ok := q.get(id)
+	if !ok {
+		return nil, false
+	}
+	return tx.txHash, true
+}
+
+// get returns the pending transaction for id, if any, without removing it.
+func (q *Queue) get(id int) (*relayMessageTx, bool) {
+	values := q.fifo
+	if q.policy != SenderPolicyFIFO {
+		values = q.heap
+	}
+	for _, tx := range values {
+		if tx.id == id {
+			return tx, true
+		}
+	}
+	return nil, false
+}
+
+func (q *Queue) isEmpty() bool {
+	return q.len() == 0
+}
+
+func (q *Queue) len() int {
+	if q.policy == SenderPolicyFIFO {
+		return len(q.fifo)
+	}
+	return len(q.heap)
+}
+
+// stuck returns the pending transactions that have sat unconfirmed for at
+// least after, highest priority first.
+func (q *Queue) stuck(after time.Duration) []*relayMessageTx {
+	values := q.fifo
+	if q.policy != SenderPolicyFIFO {
+		values = q.heap
+	}
+	now := time.Now()
+	var out []*relayMessageTx
+	for _, tx := range values {
+		if now.Sub(tx.enqueued) >= after {
+			out = append(out, tx)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return queueHeap(out).Less(i, j) })
+	return out
+}
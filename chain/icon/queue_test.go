@@ -0,0 +1,85 @@
+/*
+* Copyright 2021 ICON Foundation
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package icon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueFIFOOrder(t *testing.T) {
+	q := NewQueue(SenderPolicyFIFO)
+	q.enqueue(1, []byte("h1"))
+	q.enqueue(2, []byte("h2"))
+	q.enqueue(3, []byte("h3"))
+
+	if q.len() != 3 || q.fifo[0].id != 1 {
+		t.Fatalf("expected to find id 1 first in FIFO order, got %+v", q.fifo)
+	}
+
+	q.dequeue(1)
+	if _, ok := q.get(1); ok {
+		t.Fatalf("expected id 1 to be removed after dequeue")
+	}
+	if _, ok := q.get(2); !ok {
+		t.Fatalf("expected id 2 to remain after dequeuing id 1")
+	}
+}
+
+func TestQueuePriorityOrder(t *testing.T) {
+	q := NewQueue(SenderPolicyPriority)
+	q.enqueue(1, []byte("h1"), withPriority(3))
+	q.enqueue(2, []byte("h2"), withPriority(1))
+	q.enqueue(3, []byte("h3"), withPriority(2))
+
+	if q.heap.Len() != 3 {
+		t.Fatalf("expected 3 entries in heap, got %d", q.heap.Len())
+	}
+	if q.heap[0].id != 2 {
+		t.Fatalf("expected lowest priority (id 2) to sort first, got id %d", q.heap[0].id)
+	}
+}
+
+func TestQueueStuck(t *testing.T) {
+	q := NewQueue(SenderPolicyFeeBump)
+	q.enqueue(1, []byte("h1"))
+	q.enqueue(2, []byte("h2"))
+
+	tx, ok := q.get(2)
+	if !ok {
+		t.Fatalf("expected id 2 to be enqueued")
+	}
+	tx.enqueued = time.Now().Add(-time.Hour)
+
+	stuck := q.stuck(time.Minute)
+	if len(stuck) != 1 || stuck[0].id != 2 {
+		t.Fatalf("expected only id 2 to be reported stuck, got %+v", stuck)
+	}
+}
+
+func TestQueueTxHash(t *testing.T) {
+	q := NewQueue(SenderPolicyFIFO)
+	q.enqueue(1, []byte("deadbeef"))
+
+	h, ok := q.txHash(1)
+	if !ok || string(h) != "deadbeef" {
+		t.Fatalf("expected txHash for id 1 to be deadbeef, got %q ok:%v", h, ok)
+	}
+	if _, ok := q.txHash(404); ok {
+		t.Fatalf("expected no txHash for unknown id")
+	}
+}
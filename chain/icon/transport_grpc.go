@@ -0,0 +1,115 @@
+/*
+* Copyright 2021 ICON Foundation
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package icon
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/icon-project/btp2/chain/icon/gw"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+// grpcTransport is a types.RelayTransport that ships relay messages to a
+// BMC gateway over a single long-lived bidirectional gw.BMCGateway/Relay
+// stream, instead of one JSON-RPC call per message. The gateway's own
+// HTTP/2 flow control gates Send when it falls behind, which is exactly
+// the back-pressure Link.sendRelayMessage needs; results are pushed back
+// on the same stream as the gateway finalizes them rather than being
+// polled for.
+type grpcTransport struct {
+	conn   *grpc.ClientConn
+	stream gw.BMCGateway_RelayClient
+	cancel context.CancelFunc
+	l      log.Logger
+	rr     chan *types.RelayResult
+	wg     sync.WaitGroup
+}
+
+// newGrpcTransport dials endpoint and opens the relay stream. The dial and
+// stream are kept open for the lifetime of the transport; Close tears both
+// down.
+func newGrpcTransport(endpoint string, l log.Logger) (types.RelayTransport, error) {
+	conn, err := grpc.Dial(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := gw.NewBMCGatewayClient(conn).Relay(ctx)
+	if err != nil {
+		cancel()
+		conn.Close()
+		return nil, err
+	}
+
+	t := &grpcTransport{
+		conn:   conn,
+		stream: stream,
+		cancel: cancel,
+		l:      l,
+		rr:     make(chan *types.RelayResult),
+	}
+	t.wg.Add(1)
+	go t.recvLoop()
+	return t, nil
+}
+
+func (t *grpcTransport) Send(rm types.RelayMessage) error {
+	return t.stream.Send(&gw.RelayEnvelope{
+		Id:      int64(rm.Id()),
+		Message: rm.Bytes(),
+	})
+}
+
+func (t *grpcTransport) Results() <-chan *types.RelayResult {
+	return t.rr
+}
+
+// Close tears down the stream and connection, then waits for recvLoop to
+// observe the resulting Recv error and exit. recvLoop, not Close, closes
+// t.rr: closing it here instead would race a recvLoop that is still
+// blocked sending a result it had already received, and send on a closed
+// channel panics.
+func (t *grpcTransport) Close() error {
+	t.cancel()
+	err := t.conn.Close()
+	t.wg.Wait()
+	return err
+}
+
+func (t *grpcTransport) recvLoop() {
+	defer t.wg.Done()
+	defer close(t.rr)
+	for {
+		rr, err := t.stream.Recv()
+		if err != nil {
+			t.l.Debugf("relay stream closed err:%+v", err)
+			return
+		}
+		t.rr <- &types.RelayResult{
+			Id:        int(rr.Id),
+			Err:       errors.Code(rr.ErrorCode),
+			Finalized: rr.Finalized,
+		}
+	}
+}
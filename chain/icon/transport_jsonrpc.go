@@ -0,0 +1,382 @@
+/*
+* Copyright 2021 ICON Foundation
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package icon
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/icon-project/btp2/chain/icon/client"
+	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/jsonrpc"
+	"github.com/icon-project/btp2/common/log"
+	"github.com/icon-project/btp2/common/types"
+)
+
+// jsonrpcTransport is the types.RelayTransport implementation backed by the
+// ICON JSON-RPC `icx_sendTransaction`/`icx_getTransactionResult` calls. It is
+// the original, request/response shaped way a sender ships a RelayMessage,
+// kept as the default transport so existing deployments are unaffected.
+type jsonrpcTransport struct {
+	c            *client.Client
+	src          types.BtpAddress
+	dst          types.BtpAddress
+	w            client.Wallet
+	l            log.Logger
+	stepLimit    int64
+	maxStepLimit int64
+	stuckAfter   time.Duration
+	rr           chan *types.RelayResult
+	mtx          sync.Mutex
+	queue        *Queue
+	pending      map[int]types.RelayMessage
+	closing      chan struct{}
+	closed       bool
+	wg           sync.WaitGroup
+}
+
+func newJsonrpcTransport(c *client.Client, src, dst types.BtpAddress, w client.Wallet, stepLimit, maxStepLimit int64, policy SenderPolicy, l log.Logger) types.RelayTransport {
+	t := &jsonrpcTransport{
+		c:            c,
+		src:          src,
+		dst:          dst,
+		w:            w,
+		l:            l,
+		stepLimit:    stepLimit,
+		maxStepLimit: maxStepLimit,
+		stuckAfter:   DefaultStuckAfter * DefaultGetRelayResultInterval,
+		rr:           make(chan *types.RelayResult),
+		queue:        NewQueue(policy),
+		pending:      make(map[int]types.RelayMessage),
+		closing:      make(chan struct{}),
+	}
+	if policy == SenderPolicyFeeBump {
+		t.wg.Add(1)
+		go t.watchStuck()
+	}
+	return t
+}
+
+// spawnResult starts a result waiter for (id, txh, gen), unless the
+// transport is already closing: Close waits for every spawned waiter to
+// finish before it closes t.rr, so a waiter spawned after that wait began
+// could still send on the closed channel and panic.
+func (t *jsonrpcTransport) spawnResult(id int, txh *client.TransactionHashParam, gen int) {
+	t.mtx.Lock()
+	if t.closed {
+		t.mtx.Unlock()
+		return
+	}
+	t.wg.Add(1)
+	t.mtx.Unlock()
+
+	go func() {
+		defer t.wg.Done()
+		t.result(id, txh, gen)
+	}()
+}
+
+func (t *jsonrpcTransport) Send(rm types.RelayMessage) error {
+	t.mtx.Lock()
+	full := MaxQueueSize <= t.queue.len()
+	t.mtx.Unlock()
+	if full {
+		return errors.InvalidStateError.New("pending queue full")
+	}
+	t.l.Debugf("_relay src address:%s, rm id:%d, rm msg:%s", t.src.String(), rm.Id(), hex.EncodeToString(rm.Bytes()[:]))
+
+	thp, err := t._relay(rm, t.stepLimit)
+	if err != nil {
+		return err
+	}
+
+	b, err := thp.Hash.Value()
+	if err != nil {
+		return err
+	}
+
+	var opts []txOption
+	opts = append(opts, withStepLimit(t.stepLimit))
+	if p, ok := rm.(prioritized); ok {
+		opts = append(opts, withPriority(p.Priority()))
+	}
+
+	t.mtx.Lock()
+	t.queue.enqueue(rm.Id(), b, opts...)
+	t.pending[rm.Id()] = rm
+	t.mtx.Unlock()
+
+	t.spawnResult(rm.Id(), thp, 0)
+	return nil
+}
+
+// watchStuck periodically resubmits, with a higher StepLimit, any pending
+// transaction that has sat unconfirmed past stuckAfter. It mirrors the
+// replace-by-fee pattern common in EVM relayers: ICON has no mempool
+// replacement by nonce, so this re-signs and submits a fresh transaction
+// for the same relay message instead, updating its existing queue entry
+// in place. It stops as soon as
+// Close signals t.closing, rather than running until the process exits.
+func (t *jsonrpcTransport) watchStuck() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(DefaultGetRelayResultInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.closing:
+			return
+		case <-ticker.C:
+			t.mtx.Lock()
+			stuck := t.queue.stuck(t.stuckAfter)
+			t.mtx.Unlock()
+			for _, tx := range stuck {
+				t.resend(tx)
+			}
+		}
+	}
+}
+
+func (t *jsonrpcTransport) resend(tx *relayMessageTx) {
+	if tx.stepLimit >= t.maxStepLimit {
+		return
+	}
+	t.mtx.Lock()
+	rm, ok := t.pending[tx.id]
+	t.mtx.Unlock()
+	if !ok {
+		return
+	}
+	newStepLimit := tx.stepLimit * 2
+	if newStepLimit > t.maxStepLimit {
+		newStepLimit = t.maxStepLimit
+	}
+	t.l.Debugf("relay message id:%d stuck for %s, resubmitting with StepLimit:%d (was %d)",
+		tx.id, t.stuckAfter, newStepLimit, tx.stepLimit)
+
+	thp, err := t._relay(rm, newStepLimit)
+	if err != nil {
+		t.l.Debugf("fail to resubmit rm id:%d err:%+v", tx.id, err)
+		return
+	}
+
+	t.mtx.Lock()
+	if b, err := thp.Hash.Value(); err == nil {
+		tx.txHash = b
+	}
+	tx.stepLimit = newStepLimit
+	tx.enqueued = time.Now()
+	tx.resends++
+	tx.generation++
+	gen := tx.generation
+	t.mtx.Unlock()
+
+	// The goroutine waiting on the superseded tx hash is left running: it
+	// may still be blocked in GetResult. result() checks gen against the
+	// tx's current generation before acting, so a late result for the old
+	// hash is discarded instead of being delivered twice or dequeuing a tx
+	// this resend already replaced.
+	t.spawnResult(tx.id, thp, gen)
+}
+
+// TxHash reports the tx hash jsonrpcTransport last submitted for id, if it
+// is still pending; link.Journal persists it via TxHashReporter.
+func (t *jsonrpcTransport) TxHash(id int) ([]byte, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.queue.txHash(id)
+}
+
+func (t *jsonrpcTransport) Results() <-chan *types.RelayResult {
+	return t.rr
+}
+
+// Close stops watchStuck and waits for every spawned result waiter to
+// return before closing t.rr, so none of them can send on it after it's
+// closed (the same send-on-closed-channel race fixed for grpcTransport).
+func (t *jsonrpcTransport) Close() error {
+	t.mtx.Lock()
+	t.closed = true
+	t.mtx.Unlock()
+	close(t.closing)
+	t.wg.Wait()
+	close(t.rr)
+	return nil
+}
+
+func (t *jsonrpcTransport) _relay(rm types.RelayMessage, stepLimit int64) (*client.TransactionHashParam, error) {
+	msg := rm.Bytes()
+	idx := len(msg) / txSizeLimit
+
+	if idx == 0 {
+		rmp := &client.BMCRelayMethodParams{
+			Prev:     t.src.String(),
+			Messages: base64.URLEncoding.EncodeToString(msg),
+		}
+		return t.sendTransaction(t.newTransactionParam(client.BMCRelayMethod, rmp, stepLimit))
+	} else {
+		thp, err := t.sendFragment(msg[:txSizeLimit], idx*-1, stepLimit)
+		if err != nil {
+			return nil, err
+		}
+		msg = msg[txSizeLimit:]
+		for idx--; idx > 0; idx-- {
+			if thp, err = t.sendFragment(msg[:txSizeLimit], idx, stepLimit); err != nil {
+				return thp, err
+			}
+			msg = msg[txSizeLimit:]
+		}
+		if thp, err = t.sendFragment(msg[:], idx, stepLimit); err != nil {
+			return nil, err
+		}
+		return thp, err
+	}
+}
+
+// result waits for txh's outcome and reports it, unless id has already been
+// handled: either resend superseded it (gen no longer matches) or the
+// resubmission's own result call already dequeued id entirely, which
+// happens when that fee-bumped tx confirms first, the expected happy path.
+func (t *jsonrpcTransport) result(id int, txh *client.TransactionHashParam, gen int) {
+	_, err := t.GetResult(txh)
+
+	t.mtx.Lock()
+	if tx, ok := t.queue.get(id); !ok || tx.generation != gen {
+		t.mtx.Unlock()
+		return
+	}
+	t.queue.dequeue(id)
+	delete(t.pending, id)
+	t.mtx.Unlock()
+
+	if err != nil {
+		t.l.Debugf("result fail rm id : %d ", id)
+
+		if ec, ok := errors.CoderOf(err); ok {
+			t.rr <- &types.RelayResult{
+				Id:        id,
+				Err:       ec.ErrorCode(),
+				Finalized: true,
+			}
+		}
+	} else {
+		t.l.Debugf("result success rm id : %d ", id)
+		t.rr <- &types.RelayResult{
+			Id:        id,
+			Err:       -1,
+			Finalized: true,
+		}
+	}
+}
+
+func (t *jsonrpcTransport) newTransactionParam(method string, params interface{}, stepLimit int64) *client.TransactionParam {
+	p := &client.TransactionParam{
+		Version:     client.NewHexInt(client.JsonrpcApiVersion),
+		FromAddress: client.Address(t.w.Address()),
+		ToAddress:   client.Address(t.dst.Account()),
+		NetworkID:   client.HexInt(t.dst.NetworkID()),
+		StepLimit:   client.NewHexInt(stepLimit),
+		DataType:    "call",
+		Data: &client.CallData{
+			Method: method,
+			Params: params,
+		},
+	}
+	return p
+}
+
+func (t *jsonrpcTransport) sendFragment(msg []byte, idx int, stepLimit int64) (*client.TransactionHashParam, error) {
+	fmp := &client.BMCFragmentMethodParams{
+		Prev:     t.src.String(),
+		Messages: base64.URLEncoding.EncodeToString(msg),
+		Index:    client.NewHexInt(int64(idx)),
+	}
+	p := t.newTransactionParam(client.BMCFragmentMethod, fmp, stepLimit)
+	return t.sendTransaction(p)
+}
+
+func (t *jsonrpcTransport) sendTransaction(p *client.TransactionParam) (*client.TransactionHashParam, error) {
+	thp := &client.TransactionHashParam{}
+SignLoop:
+	for {
+		if err := t.c.SignTransaction(t.w, p); err != nil {
+			return nil, err
+		}
+	SendLoop:
+		for {
+			txh, err := t.c.SendTransaction(p)
+			if txh != nil {
+				thp.Hash = *txh
+			}
+			if err != nil {
+				if je, ok := err.(*jsonrpc.Error); ok {
+					switch je.Code {
+					case client.JsonrpcErrorCodeTxPoolOverflow:
+						<-time.After(DefaultRelayReSendInterval)
+						continue SendLoop
+					case client.JsonrpcErrorCodeSystem:
+						if subEc, err := strconv.ParseInt(je.Message[1:5], 0, 32); err == nil {
+							switch subEc {
+							case client.DuplicateTransactionError:
+								t.l.Debugf("DuplicateTransactionError txh:%v", txh)
+								return thp, nil
+							case client.ExpiredTransactionError:
+								continue SignLoop
+							}
+						}
+					}
+				}
+				return nil, client.MapError(err)
+			}
+			return thp, nil
+		}
+	}
+}
+
+func (t *jsonrpcTransport) GetResult(txh *client.TransactionHashParam) (*client.TransactionResult, error) {
+	for {
+		txr, err := t.c.GetTransactionResult(txh)
+		if err != nil {
+			if je, ok := err.(*jsonrpc.Error); ok {
+				switch je.Code {
+				//TODO add notFound timeout
+				case client.JsonrpcErrorCodePending, client.JsonrpcErrorCodeExecuting, client.JsonrpcErrorCodeNotFound:
+					<-time.After(DefaultGetRelayResultInterval)
+					continue
+				}
+			}
+		}
+		return txr, mapErrorWithTransactionResult(txr, err)
+	}
+}
+
+func mapErrorWithTransactionResult(txr *client.TransactionResult, err error) error {
+	err = client.MapError(err)
+	if err == nil && txr != nil && txr.Status != client.ResultStatusSuccess {
+		fc, _ := txr.Failure.CodeValue.Value()
+		if fc < client.ResultStatusFailureCodeRevert || fc > client.ResultStatusFailureCodeEnd {
+			err = fmt.Errorf("failure with code:%s, message:%s",
+				txr.Failure.CodeValue, txr.Failure.MessageValue)
+		} else {
+			err = errors.NewRevertError(int(fc - client.ResultStatusFailureCodeRevert))
+		}
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package link
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/icon-project/btp2/common/types"
+)
+
+// debugRelayMessage is the JSON-safe projection of a relayMessage exposed
+// by DebugHandler; it omits the raw message bytes and rmis, which aren't
+// useful for live troubleshooting and can be large.
+type debugRelayMessage struct {
+	Id            int                  `json:"id"`
+	BMCLinkStatus *types.BMCLinkStatus `json:"bmc_link_status"`
+	BpHeight      int64                `json:"bp_height"`
+	Size          int                  `json:"size"`
+	SendingStatus bool                 `json:"sending_status"`
+}
+
+type debugReceiveStatus struct {
+	Height int64 `json:"height"`
+	Seq    int64 `json:"seq"`
+	MsgCnt int64 `json:"msg_cnt"`
+}
+
+type debugState struct {
+	Src           string               `json:"src"`
+	Dst           string               `json:"dst"`
+	RelayState    RelayState           `json:"relay_state"`
+	BMCLinkStatus *types.BMCLinkStatus `json:"bmc_link_status"`
+	RelayMessages []debugRelayMessage  `json:"relay_messages"`
+	ReceiveStatus []debugReceiveStatus `json:"receive_status"`
+}
+
+// DebugHandler returns an http.Handler that dumps l's current state (last
+// BMCLinkStatus, pending relay messages and ReceiveStatus backlog) as
+// JSON, for mounting at a path such as /debug/relay when log.Debugf output
+// isn't enough for live troubleshooting.
+func (l *Link) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		l.rmsMtx.RLock()
+		defer l.rmsMtx.RUnlock()
+
+		rms := make([]debugRelayMessage, len(l.rms))
+		for i, rm := range l.rms {
+			rms[i] = debugRelayMessage{
+				Id:            rm.id,
+				BMCLinkStatus: rm.bls,
+				BpHeight:      rm.bpHeight,
+				Size:          len(rm.message),
+				SendingStatus: rm.sendingStatus,
+			}
+		}
+
+		rss := make([]debugReceiveStatus, len(l.rss))
+		for i, rs := range l.rss {
+			rss[i] = debugReceiveStatus{Height: rs.height, Seq: rs.seq, MsgCnt: rs.msgCnt}
+		}
+
+		state := &debugState{
+			Src:           l.src.String(),
+			Dst:           l.dst.String(),
+			RelayState:    l.relayState,
+			BMCLinkStatus: l.bls,
+			RelayMessages: rms,
+			ReceiveStatus: rss,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(state); err != nil {
+			l.l.Debugf("fail to encode debug state err:%+v", err)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package link
+
+import (
+	"sync"
+
+	"github.com/icon-project/btp2/common/log"
+)
+
+// HeaderSync is a finalized-header cache keyed by source chain network ID.
+// A Link only ever fetches and caches headers of its own src chain, so
+// sharing one HeaderSync amortizes cost across Links that share a common
+// src chain (e.g. one process relaying from chain A to several
+// destinations B, C, ...), not across the two opposite-direction Links of
+// a single A<->B pair, which never touch each other's keyspace. Link stores
+// every header it fetches via Store, and checks the cache via
+// TryGetTrustedHeader before fetching one itself.
+type HeaderSync struct {
+	l    log.Logger
+	mtx  sync.RWMutex
+	hdrs map[string]map[int64]BlockUpdate
+	tip  map[string]int64
+}
+
+func NewHeaderSync(l log.Logger) *HeaderSync {
+	return &HeaderSync{
+		l:    l,
+		hdrs: make(map[string]map[int64]BlockUpdate),
+		tip:  make(map[string]int64),
+	}
+}
+
+// Store records bu as the trusted header for chainID at height, making it
+// visible to TryGetTrustedHeader.
+func (h *HeaderSync) Store(chainID string, height int64, bu BlockUpdate) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if _, ok := h.hdrs[chainID]; !ok {
+		h.hdrs[chainID] = make(map[int64]BlockUpdate)
+	}
+	h.hdrs[chainID][height] = bu
+	if height > h.tip[chainID] {
+		h.tip[chainID] = height
+	}
+	h.l.Debugf("HeaderSync cached chain:%s height:%d", chainID, height)
+}
+
+// TryGetTrustedHeader returns the cached header for chainID at height
+// without blocking.
+func (h *HeaderSync) TryGetTrustedHeader(chainID string, height int64) (BlockUpdate, bool) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	c, ok := h.hdrs[chainID]
+	if !ok {
+		return nil, false
+	}
+	bu, ok := c[height]
+	return bu, ok
+}
+
+// Invalidate drops cached headers for chainID at or above fromHeight. Link
+// calls this when result() observes BMVRevertInvalidBlockWitnessOld,
+// meaning a previously cached header is no longer trusted.
+func (h *HeaderSync) Invalidate(chainID string, fromHeight int64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	c, ok := h.hdrs[chainID]
+	if !ok {
+		return
+	}
+	for height := range c {
+		if height >= fromHeight {
+			delete(c, height)
+		}
+	}
+	if h.tip[chainID] >= fromHeight {
+		h.tip[chainID] = fromHeight - 1
+	}
+}
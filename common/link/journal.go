@@ -0,0 +1,230 @@
+package link
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/icon-project/btp2/common/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Journal persists relay messages as they are built and sent, so Link.Start
+// can rehydrate pending ones after a crash or restart instead of
+// recomputing everything from GetStatus() and re-sending transactions that
+// may already be sitting in the destination mempool.
+type Journal interface {
+	// AppendRelayMessage records a newly built, not-yet-sent rm.
+	AppendRelayMessage(rm *relayMessage) error
+	// MarkSent records that the relay message with id has been handed to
+	// the sender, along with the tx hash the sender reported for it (nil
+	// if the sender doesn't implement TxHashReporter). The hash is kept
+	// for forensic/debug purposes only: LoadPending always rehydrates
+	// with sendingStatus cleared, since no goroutine survives a restart
+	// to wait on it, so Link simply re-sends instead of resuming a wait
+	// on the old hash.
+	MarkSent(id int, txHash []byte) error
+	// MarkFinalized removes the relay message with id from the journal;
+	// it has been confirmed on the destination and never needs replaying.
+	MarkFinalized(id int) error
+	// LoadPending returns every relay message the journal still has that
+	// hasn't been marked finalized, ordered by (Verifier.Height, RxSeq)
+	// ascending, for Link.Start to rehydrate into l.rms in the same order
+	// they were originally built and sent.
+	LoadPending() ([]*relayMessage, error)
+	// Compact drops entries bls has already advanced past, bounding
+	// on-disk growth; Link calls it whenever it clears its in-memory
+	// relay message backlog for the same reason.
+	Compact(bls *types.BMCLinkStatus) error
+	Close() error
+}
+
+// TxHashReporter is implemented by a types.Sender that can report the tx
+// hash it submitted for a relay message id, so Journal.MarkSent can
+// persist it alongside the message. Link type-asserts for it, mirroring
+// the prioritized/SeqMessageProofBuilder optional-interface pattern used
+// elsewhere for sender/receiver capabilities that aren't universal.
+type TxHashReporter interface {
+	TxHash(id int) ([]byte, bool)
+}
+
+// noopJournal is the Journal NewLink uses when WithJournal isn't given,
+// preserving the original in-memory-only behavior.
+type noopJournal struct{}
+
+func (noopJournal) AppendRelayMessage(*relayMessage) error { return nil }
+func (noopJournal) MarkSent(int, []byte) error             { return nil }
+func (noopJournal) MarkFinalized(int) error                { return nil }
+func (noopJournal) LoadPending() ([]*relayMessage, error)  { return nil, nil }
+func (noopJournal) Compact(*types.BMCLinkStatus) error     { return nil }
+func (noopJournal) Close() error                           { return nil }
+
+var relayMessagesBucket = []byte("relay_messages")
+
+// journalRecord is the on-disk form of a relayMessage. rmis is
+// intentionally not persisted: it is only needed to build message bytes,
+// and those are already captured in Message, so a rehydrated relayMessage
+// can be resent (and, if already in the destination mempool, deduplicated
+// there) without recomputing it.
+type journalRecord struct {
+	Id            int                  `json:"id"`
+	Bls           *types.BMCLinkStatus `json:"bls"`
+	BpHeight      int64                `json:"bp_height"`
+	Message       []byte               `json:"message"`
+	SendingStatus bool                 `json:"sending_status"`
+	TxHash        []byte               `json:"tx_hash,omitempty"`
+}
+
+// BoltJournal is the Journal implementation backed by bbolt, a single-file,
+// zero-dependency, pure-Go key-value store.
+type BoltJournal struct {
+	db *bolt.DB
+}
+
+// NewBoltJournal opens (creating if necessary) a bbolt-backed Journal at
+// path.
+func NewBoltJournal(path string) (*BoltJournal, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(relayMessagesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltJournal{db: db}, nil
+}
+
+func journalKey(id int) []byte {
+	return []byte(fmt.Sprintf("%020d", uint64(id)))
+}
+
+func (j *BoltJournal) put(rec *journalRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return j.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(relayMessagesBucket).Put(journalKey(rec.Id), b)
+	})
+}
+
+func (j *BoltJournal) get(id int) (*journalRecord, error) {
+	var rec *journalRecord
+	err := j.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(relayMessagesBucket).Get(journalKey(id))
+		if v == nil {
+			return nil
+		}
+		rec = &journalRecord{}
+		return json.Unmarshal(v, rec)
+	})
+	return rec, err
+}
+
+func (j *BoltJournal) AppendRelayMessage(rm *relayMessage) error {
+	return j.put(&journalRecord{
+		Id:            rm.id,
+		Bls:           rm.bls,
+		BpHeight:      rm.bpHeight,
+		Message:       rm.message,
+		SendingStatus: rm.sendingStatus,
+	})
+}
+
+func (j *BoltJournal) MarkSent(id int, txHash []byte) error {
+	rec, err := j.get(id)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return nil
+	}
+	rec.SendingStatus = true
+	rec.TxHash = txHash
+	return j.put(rec)
+}
+
+func (j *BoltJournal) MarkFinalized(id int) error {
+	return j.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(relayMessagesBucket).Delete(journalKey(id))
+	})
+}
+
+func (j *BoltJournal) LoadPending() ([]*relayMessage, error) {
+	var out []*relayMessage
+	err := j.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(relayMessagesBucket).ForEach(func(k, v []byte) error {
+			rec := &journalRecord{}
+			if err := json.Unmarshal(v, rec); err != nil {
+				return err
+			}
+			// sendingStatus is always rehydrated false: whatever goroutine
+			// was waiting on rec.TxHash died with the old process, and
+			// sendRelayMessage only (re)sends messages with
+			// sendingStatus==false. Without this, a message sent but not
+			// yet finalized before the crash would sit in l.rms forever,
+			// since nothing would ever send or wait on it again. Re-sending
+			// is safe: the sender's duplicate-transaction handling
+			// deduplicates against the original if it's still pending.
+			out = append(out, &relayMessage{
+				id:            rec.Id,
+				bls:           rec.Bls,
+				bpHeight:      rec.BpHeight,
+				message:       rec.Message,
+				sendingStatus: false,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Keys are journalKey(rec.Id), and id is a random int (see
+	// appendRelayMessage/RelaySequences), so ForEach's byte-order iteration
+	// does not reflect build/height order. sendRelayMessage relays l.rms in
+	// slice order and clearRelayMessage assumes it is ascending by height,
+	// so sort explicitly instead of relying on bucket order.
+	sort.Slice(out, func(i, j int) bool {
+		bi, bj := out[i].bls, out[j].bls
+		if bi.Verifier.Height != bj.Verifier.Height {
+			return bi.Verifier.Height < bj.Verifier.Height
+		}
+		return bi.RxSeq < bj.RxSeq
+	})
+	return out, nil
+}
+
+func (j *BoltJournal) Compact(bls *types.BMCLinkStatus) error {
+	return j.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(relayMessagesBucket)
+		var stale [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			rec := &journalRecord{}
+			if err := json.Unmarshal(v, rec); err != nil {
+				return err
+			}
+			if rec.Bls != nil && rec.Bls.Verifier.Height <= bls.Verifier.Height && rec.Bls.RxSeq <= bls.RxSeq {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (j *BoltJournal) Close() error {
+	return j.db.Close()
+}
@@ -0,0 +1,114 @@
+package link
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/icon-project/btp2/common/types"
+)
+
+func newTestJournal(t *testing.T) *BoltJournal {
+	t.Helper()
+	j, err := NewBoltJournal(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatalf("NewBoltJournal() err:%+v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func TestBoltJournalAppendAndLoadPending(t *testing.T) {
+	j := newTestJournal(t)
+
+	rm := &relayMessage{
+		id:      1,
+		bls:     &types.BMCLinkStatus{RxSeq: 10},
+		message: []byte("msg-1"),
+	}
+	if err := j.AppendRelayMessage(rm); err != nil {
+		t.Fatalf("AppendRelayMessage() err:%+v", err)
+	}
+
+	pending, err := j.LoadPending()
+	if err != nil {
+		t.Fatalf("LoadPending() err:%+v", err)
+	}
+	if len(pending) != 1 || pending[0].id != 1 || string(pending[0].message) != "msg-1" {
+		t.Fatalf("expected to load back the appended message, got %+v", pending)
+	}
+	if pending[0].sendingStatus {
+		t.Fatalf("expected sendingStatus to always load false, even before MarkSent")
+	}
+}
+
+func TestBoltJournalMarkSentClearsOnLoad(t *testing.T) {
+	j := newTestJournal(t)
+
+	rm := &relayMessage{id: 2, bls: &types.BMCLinkStatus{RxSeq: 20}, message: []byte("msg-2")}
+	if err := j.AppendRelayMessage(rm); err != nil {
+		t.Fatalf("AppendRelayMessage() err:%+v", err)
+	}
+	if err := j.MarkSent(2, []byte("txhash")); err != nil {
+		t.Fatalf("MarkSent() err:%+v", err)
+	}
+
+	pending, err := j.LoadPending()
+	if err != nil {
+		t.Fatalf("LoadPending() err:%+v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected the sent-but-not-finalized message to still be pending, got %+v", pending)
+	}
+	if pending[0].sendingStatus {
+		t.Fatalf("expected sendingStatus to be cleared on load, so it is resent rather than orphaned")
+	}
+}
+
+func TestBoltJournalMarkFinalizedRemoves(t *testing.T) {
+	j := newTestJournal(t)
+
+	rm := &relayMessage{id: 3, bls: &types.BMCLinkStatus{RxSeq: 30}, message: []byte("msg-3")}
+	if err := j.AppendRelayMessage(rm); err != nil {
+		t.Fatalf("AppendRelayMessage() err:%+v", err)
+	}
+	if err := j.MarkFinalized(3); err != nil {
+		t.Fatalf("MarkFinalized() err:%+v", err)
+	}
+
+	pending, err := j.LoadPending()
+	if err != nil {
+		t.Fatalf("LoadPending() err:%+v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected finalized message to be gone, got %+v", pending)
+	}
+}
+
+func TestBoltJournalCompact(t *testing.T) {
+	j := newTestJournal(t)
+
+	stale := &relayMessage{id: 4, bls: &types.BMCLinkStatus{RxSeq: 5}, message: []byte("stale")}
+	stale.bls.Verifier.Height = 100
+	fresh := &relayMessage{id: 5, bls: &types.BMCLinkStatus{RxSeq: 50}, message: []byte("fresh")}
+	fresh.bls.Verifier.Height = 200
+	if err := j.AppendRelayMessage(stale); err != nil {
+		t.Fatalf("AppendRelayMessage(stale) err:%+v", err)
+	}
+	if err := j.AppendRelayMessage(fresh); err != nil {
+		t.Fatalf("AppendRelayMessage(fresh) err:%+v", err)
+	}
+
+	bls := &types.BMCLinkStatus{RxSeq: 10}
+	bls.Verifier.Height = 120
+	if err := j.Compact(bls); err != nil {
+		t.Fatalf("Compact() err:%+v", err)
+	}
+
+	pending, err := j.LoadPending()
+	if err != nil {
+		t.Fatalf("LoadPending() err:%+v", err)
+	}
+	if len(pending) != 1 || pending[0].id != 5 {
+		t.Fatalf("expected only the fresh message to survive compaction, got %+v", pending)
+	}
+}
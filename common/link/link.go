@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/icon-project/btp2/chain"
 	"github.com/icon-project/btp2/common/errors"
+	"github.com/icon-project/btp2/common/link/metrics"
 	"github.com/icon-project/btp2/common/log"
 	"github.com/icon-project/btp2/common/types"
 )
@@ -25,6 +27,8 @@ type relayMessage struct {
 	message       []byte
 	rmis          []RelayMessageItem
 	sendingStatus bool
+	builtAt       time.Time
+	sentAt        time.Time
 }
 
 func (r *relayMessage) Id() int {
@@ -51,6 +55,15 @@ func (r *relayMessage) RelayMessageItems() []RelayMessageItem {
 	return r.rmis
 }
 
+// Priority ranks this relay message against others pending send: lower
+// values are relayed first. It is derived from the oldest RxSeq the
+// message advances the destination past, so senders running in
+// SenderPolicyPriority/FeeBump modes clear the backlog oldest-first
+// instead of relying on enqueue order.
+func (r *relayMessage) Priority() int64 {
+	return r.bls.RxSeq
+}
+
 type relayMessageItem struct {
 	rmis [][]RelayMessageItem
 	size int64
@@ -86,9 +99,36 @@ type Link struct {
 	bls        *types.BMCLinkStatus
 	blsChannel chan *types.BMCLinkStatus
 	relayState RelayState
+	hsync      *HeaderSync
+	journal    Journal
+	metrics    *metrics.Recorder
+}
+
+// LinkOption configures optional Link dependencies. NewLink applies them in
+// order after building the Link with its defaults.
+type LinkOption func(*Link)
+
+// WithHeaderSync shares hsync with other Link instances that relay from
+// the same src chain (e.g. one process relaying chain A to several
+// destinations), so they amortize header fetch/verification cost instead
+// of each fetching and verifying the same src headers independently.
+// Sharing hsync between the two opposite-direction Links of a single
+// pair has no effect: each only ever caches and reads its own src chain's
+// keyspace. Without WithHeaderSync, a Link fetches headers independently,
+// as it always has.
+func WithHeaderSync(hsync *HeaderSync) LinkOption {
+	return func(l *Link) { l.hsync = hsync }
 }
 
-func NewLink(cfg *chain.Config, r Receiver, l log.Logger) types.Link {
+// WithJournal persists pending relay messages to journal as they are
+// built and sent, so Start can rehydrate them after a crash or restart
+// instead of recomputing everything from GetStatus(). Without it, a Link
+// keeps pending relay messages in memory only, as it always has.
+func WithJournal(journal Journal) LinkOption {
+	return func(l *Link) { l.journal = journal }
+}
+
+func NewLink(cfg *chain.Config, r Receiver, l log.Logger, opts ...LinkOption) types.Link {
 	link := &Link{
 		src: cfg.Src.Address,
 		dst: cfg.Dst.Address,
@@ -103,6 +143,11 @@ func NewLink(cfg *chain.Config, r Receiver, l log.Logger) types.Link {
 		},
 		blsChannel: make(chan *types.BMCLinkStatus),
 		relayState: RUNNING,
+		journal:    noopJournal{},
+	}
+	link.metrics = metrics.NewRecorder(link.src.String(), link.dst.String())
+	for _, opt := range opts {
+		opt(link)
 	}
 	link.rmi.rmis = append(link.rmi.rmis, make([]RelayMessageItem, 0))
 	return link
@@ -120,6 +165,15 @@ func (l *Link) Start(sender types.Sender) error {
 
 	l.bls = bls
 
+	if pending, err := l.journal.LoadPending(); err != nil {
+		l.l.Debugf("fail to load pending relay messages from journal err:%+v", err)
+	} else if len(pending) > 0 {
+		l.rmsMtx.Lock()
+		l.rms = append(l.rms, pending...)
+		l.rmsMtx.Unlock()
+		l.l.Debugf("rehydrated %d pending relay message(s) from journal", len(pending))
+	}
+
 	l.receiverChannel(errCh)
 
 	l.r.FinalizedStatus(l.blsChannel)
@@ -138,6 +192,9 @@ func (l *Link) Start(sender types.Sender) error {
 func (l *Link) Stop() {
 	l.s.Stop()
 	l.r.Stop()
+	if err := l.journal.Close(); err != nil {
+		l.l.Debugf("fail to close journal err:%+v", err)
+	}
 }
 
 func (l *Link) receiverChannel(errCh chan error) {
@@ -167,7 +224,7 @@ func (l *Link) receiverChannel(errCh chan error) {
 						if err = l.HandleRelayMessage(); err != nil {
 							errCh <- err
 						}
-						l.relayState = PENDING
+						l.setRelayState(PENDING)
 					})
 
 					if err = l.HandleRelayMessage(); err != nil {
@@ -214,6 +271,17 @@ func (l *Link) clearRelayMessage(bls *types.BMCLinkStatus) {
 			break
 		}
 	}
+	if err := l.journal.Compact(bls); err != nil {
+		l.l.Debugf("fail to compact journal err:%+v", err)
+	}
+}
+
+// setRelayState transitions l.relayState and reports the new state so
+// RelayState transitions are visible to the metrics package, not just
+// log.Debugf output.
+func (l *Link) setRelayState(s RelayState) {
+	l.relayState = s
+	l.metrics.SetRelayState(int(s))
 }
 
 func (l *Link) clearReceiveStatus(bls *types.BMCLinkStatus) {
@@ -263,23 +331,47 @@ func (l *Link) buildRelayMessage() error {
 func (l *Link) sendRelayMessage() error {
 	for _, rm := range l.rms {
 		if rm.sendingStatus == false {
-
-			_, err := l.s.Relay(rm)
+			sent, err := l.sendOne(rm)
 			if err != nil {
-				if errors.InvalidStateError.Equals(err) {
-					l.relayState = PENDING
-					return nil
-				} else {
-					return err
-				}
-			} else {
-				rm.sendingStatus = true
+				return err
+			}
+			if !sent {
+				return nil
 			}
 		}
 	}
 	return nil
 }
 
+// sendOne relays a single rm and, on success, marks it sent and reports it
+// to the journal. It returns false (with a nil error) when the sender is
+// PENDING and rm must wait for a later call, mirroring the InvalidStateError
+// handling sendRelayMessage has always done for the whole l.rms loop.
+func (l *Link) sendOne(rm *relayMessage) (bool, error) {
+	_, err := l.s.Relay(rm)
+	if err != nil {
+		if errors.InvalidStateError.Equals(err) {
+			l.setRelayState(PENDING)
+			return false, nil
+		}
+		return false, err
+	}
+
+	rm.sendingStatus = true
+	rm.sentAt = time.Now()
+	if !rm.builtAt.IsZero() {
+		l.metrics.ObserveBuildToSend(rm.sentAt.Sub(rm.builtAt))
+	}
+	var txHash []byte
+	if r, ok := l.s.(TxHashReporter); ok {
+		txHash, _ = r.TxHash(rm.id)
+	}
+	if err := l.journal.MarkSent(rm.id, txHash); err != nil {
+		l.l.Debugf("fail to mark relay message id:%d sent in journal err:%+v", rm.id, err)
+	}
+	return true, nil
+}
+
 func (l *Link) appendRelayMessage(bls *types.BMCLinkStatus) error {
 	for _, rmi := range l.rmi.rmis {
 		m, err := l.r.BuildRelayMessage(rmi)
@@ -296,11 +388,16 @@ func (l *Link) appendRelayMessage(bls *types.BMCLinkStatus) error {
 		}
 
 		rm.sendingStatus = false
+		rm.builtAt = time.Now()
 		l.rms = append(l.rms, rm)
+		if err := l.journal.AppendRelayMessage(rm); err != nil {
+			l.l.Debugf("fail to append relay message id:%d to journal err:%+v", rm.id, err)
+		}
 	}
 
 	l.rmi.rmis = l.rmi.rmis[:0]
 	l.resetRelayMessageItem()
+	l.metrics.SetPendingRelayMessages(len(l.rms))
 
 	return nil
 }
@@ -308,6 +405,10 @@ func (l *Link) appendRelayMessage(bls *types.BMCLinkStatus) error {
 func (l *Link) HandleRelayMessage() error {
 	l.rmsMtx.Lock()
 	defer l.rmsMtx.Unlock()
+	defer func() {
+		l.metrics.SetPendingRelayMessages(len(l.rms))
+		l.metrics.SetReceiveStatusBacklog(len(l.rss))
+	}()
 	if l.relayState == RUNNING {
 		if err := l.sendRelayMessage(); err != nil {
 			return err
@@ -328,12 +429,25 @@ func (l *Link) HandleRelayMessage() error {
 }
 
 func (l *Link) buildBlockUpdates(bs *types.BMCLinkStatus) ([]BlockUpdate, error) {
+	if l.hsync != nil {
+		if bu, ok := l.hsync.TryGetTrustedHeader(l.src.NetworkID(), bs.Verifier.Height+1); ok {
+			return []BlockUpdate{bu}, nil
+		}
+	}
 	for {
 		bus, err := l.r.BuildBlockUpdate(bs, l.limitSize-l.rmi.size)
 		if err != nil {
 			return nil, err
 		}
 		if len(bus) != 0 {
+			if l.hsync != nil {
+				tmp := *bs
+				for _, bu := range bus {
+					if err := bu.UpdateBMCLinkStatus(&tmp); err == nil {
+						l.hsync.Store(l.src.NetworkID(), tmp.Verifier.Height, bu)
+					}
+				}
+			}
 			return bus, nil
 		}
 	}
@@ -362,6 +476,10 @@ func (l *Link) handleUndeliveredRelayMessage() error {
 			}
 			break
 		} else if h < l.bls.Verifier.Height {
+			// This height is already behind the verifier, so it needs a
+			// BlockProof rather than a BlockUpdate; bu only tells buildProof
+			// whether a BlockUpdate is already covering bls.Verifier.Height,
+			// which the cache has no bearing on here.
 			err := l.buildProof(l.bls, nil)
 			if err != nil {
 				return err
@@ -500,10 +618,16 @@ func (l *Link) resetRelayMessageItem() {
 
 func (l *Link) successRelayMessage(id int) error {
 	rm := l.searchRelayMessage(id)
+	if err := l.journal.MarkFinalized(rm.id); err != nil {
+		l.l.Debugf("fail to mark relay message id:%d finalized in journal err:%+v", rm.id, err)
+	}
+	if !rm.sentAt.IsZero() {
+		l.metrics.ObserveSendToFinalized(time.Since(rm.sentAt))
+	}
 	l.clearRelayMessage(rm.BMCLinkStatus())
 	l.clearReceiveStatus(rm.BMCLinkStatus())
 
-	l.relayState = RUNNING
+	l.setRelayState(RUNNING)
 
 	err := l.HandleRelayMessage()
 	if err != nil {
@@ -524,10 +648,12 @@ func (l *Link) result(rr *types.RelayResult) error {
 			}
 		}
 	case errors.BMVUnknown:
+		l.metrics.IncError(rr.Err)
 		l.l.Panicf("BMVUnknown Revert : ErrorCoder:%+v", rr.Err)
 	case errors.BMVNotVerifiable:
+		l.metrics.IncError(rr.Err)
 		if rr.Finalized != true {
-			l.relayState = PENDING
+			l.setRelayState(PENDING)
 		} else {
 			bls, err := l.s.GetStatus()
 			if err != nil {
@@ -535,14 +661,21 @@ func (l *Link) result(rr *types.RelayResult) error {
 			}
 			l.bls = bls
 			l.clearRelayMessage(l.bls) // TODO refactoring
-			l.relayState = RUNNING
+			l.setRelayState(RUNNING)
 			l.HandleRelayMessage()
 		}
 	case errors.BMVAlreadyVerified:
 		//TODO Error handling required on Finalized
+		l.metrics.IncError(rr.Err)
 		l.removeRelayMessage(rr.Id)
 	case errors.BMVRevertInvalidBlockWitnessOld:
 		//TODO Error handling required on Finalized
+		l.metrics.IncError(rr.Err)
+		if l.hsync != nil {
+			if rm := l.searchRelayMessage(rr.Id); rm != nil {
+				l.hsync.Invalidate(l.src.NetworkID(), rm.BMCLinkStatus().Verifier.Height)
+			}
+		}
 		l.updateBlockProof(rr.Id)
 	default:
 		l.l.Panicf("fail to GetResult RelayMessage ID:%v ErrorCoder:%+v",
@@ -0,0 +1,137 @@
+// Package metrics exposes Prometheus instrumentation for the link package:
+// relay-message backlog, build/send/finalize latency, transaction size and
+// fragmentation, and RelayResult error rates. Before this package, the only
+// observability into those was log.Debugf output, which can't be scraped or
+// alerted on.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "btp2_link"
+
+var (
+	pendingRelayMessages = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_relay_messages",
+		Help:      "Relay messages built but not yet finalized on the destination (len(l.rms)).",
+	}, []string{"src", "dst"})
+
+	receiveStatusBacklog = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "receive_status_backlog",
+		Help:      "ReceiveStatus entries awaiting relay (len(l.rss)).",
+	}, []string{"src", "dst"})
+
+	relayState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "relay_state",
+		Help:      "Current RelayState of a Link: 0=RUNNING, 1=PENDING.",
+	}, []string{"src", "dst"})
+
+	buildToSendSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "build_to_send_seconds",
+		Help:      "Time from a relay message being built to being handed to the sender.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"src", "dst"})
+
+	sendToFinalizedSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "send_to_finalized_seconds",
+		Help:      "Time from a relay message being handed to the sender to being finalized on the destination.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"src", "dst"})
+
+	txSizeBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tx_size_bytes",
+		Help:      "Size of a relayed transaction's message payload, in bytes.",
+		Buckets:   prometheus.ExponentialBuckets(1024, 2, 10),
+	}, []string{"src", "dst"})
+
+	fragmentCount = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fragment_count",
+		Help:      "Number of transactions a single relay message was split into.",
+		Buckets:   prometheus.LinearBuckets(1, 1, 10),
+	}, []string{"src", "dst"})
+
+	errorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "RelayResult errors handled by Link.result, bucketed by errors.* code.",
+	}, []string{"src", "dst", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		pendingRelayMessages,
+		receiveStatusBacklog,
+		relayState,
+		buildToSendSeconds,
+		sendToFinalizedSeconds,
+		txSizeBytes,
+		fragmentCount,
+		errorsTotal,
+	)
+}
+
+// Recorder records metrics for a single Link, labeled with its src/dst
+// chain pair so a process relaying between several chains doesn't mix
+// their series together.
+type Recorder struct {
+	src, dst string
+}
+
+// NewRecorder returns a Recorder labeled with src and dst, the BtpAddress
+// strings of a Link's source and destination chains.
+func NewRecorder(src, dst string) *Recorder {
+	return &Recorder{src: src, dst: dst}
+}
+
+func (r *Recorder) SetPendingRelayMessages(n int) {
+	pendingRelayMessages.WithLabelValues(r.src, r.dst).Set(float64(n))
+}
+
+func (r *Recorder) SetReceiveStatusBacklog(n int) {
+	receiveStatusBacklog.WithLabelValues(r.src, r.dst).Set(float64(n))
+}
+
+func (r *Recorder) SetRelayState(s int) {
+	relayState.WithLabelValues(r.src, r.dst).Set(float64(s))
+}
+
+func (r *Recorder) ObserveBuildToSend(d time.Duration) {
+	buildToSendSeconds.WithLabelValues(r.src, r.dst).Observe(d.Seconds())
+}
+
+func (r *Recorder) ObserveSendToFinalized(d time.Duration) {
+	sendToFinalizedSeconds.WithLabelValues(r.src, r.dst).Observe(d.Seconds())
+}
+
+func (r *Recorder) ObserveTxSize(n int) {
+	txSizeBytes.WithLabelValues(r.src, r.dst).Observe(float64(n))
+}
+
+func (r *Recorder) ObserveFragmentCount(n int) {
+	fragmentCount.WithLabelValues(r.src, r.dst).Observe(float64(n))
+}
+
+// IncError increments the error counter for code, the value of the
+// errors.* ErrorCoder handled by Link.result.
+func (r *Recorder) IncError(code interface{}) {
+	errorsTotal.WithLabelValues(r.src, r.dst, fmt.Sprintf("%v", code)).Inc()
+}
+
+// Handler serves the registered collectors in the Prometheus exposition
+// format, for mounting at a path such as /metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,137 @@
+package link
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/icon-project/btp2/common/types"
+)
+
+// SeqMessageProofBuilder is implemented by Receivers that can build a
+// MessageProof restricted to an explicit set of BTP sequence numbers,
+// rather than the next contiguous run BuildMessageProof produces.
+// Link.RelaySequences type-asserts for it, so only Receiver implementations
+// that maintain a seq->height index need to support it.
+type SeqMessageProofBuilder interface {
+	// BuildMessageProofForSeqs builds a MessageProof covering only seqs,
+	// bounded by limit bytes.
+	BuildMessageProofForSeqs(bls *types.BMCLinkStatus, seqs []int64, limit int64) (RelayMessageItem, error)
+}
+
+// UnrelayedSequences returns the BTP sequence numbers the source side has
+// produced that the destination has not yet acknowledged, i.e. the ones
+// still pending relay. It is meant for operator tooling and integration
+// tests that need visibility into the pending queue without driving the
+// normal buildRelayMessage/sendRelayMessage loop.
+func (l *Link) UnrelayedSequences() ([]int64, error) {
+	l.rmsMtx.RLock()
+	defer l.rmsMtx.RUnlock()
+
+	if len(l.rss) == 0 {
+		return nil, nil
+	}
+	latest := l.rss[len(l.rss)-1].seq
+	rxSeq := l.bls.RxSeq
+	if latest <= rxSeq {
+		return nil, nil
+	}
+	seqs := make([]int64, 0, latest-rxSeq)
+	for seq := rxSeq + 1; seq <= latest; seq++ {
+		seqs = append(seqs, seq)
+	}
+	return seqs, nil
+}
+
+// isContiguousFromRxSeq reports whether seqs is exactly the run
+// bls.RxSeq+1..bls.RxSeq+len(seqs) in order, i.e. the same run
+// UnrelayedSequences reports and BuildMessageProof already produces.
+func isContiguousFromRxSeq(bls *types.BMCLinkStatus, seqs []int64) bool {
+	next := bls.RxSeq + 1
+	for _, seq := range seqs {
+		if seq != next {
+			return false
+		}
+		next++
+	}
+	return true
+}
+
+// RelaySequences builds and sends a relay message containing only seqs,
+// plus whatever BlockProof is needed to make it verifiable, instead of
+// flushing the full pending queue. This lets operator tooling resend one
+// stuck packet, and lets integration tests exercise out-of-order or
+// partial delivery.
+//
+// Building a proof for an arbitrary set of seqs requires the receiver to
+// maintain a seq->height index, via SeqMessageProofBuilder; no Receiver in
+// this tree implements it yet, which is a known limitation of the current
+// API. Without it, the best RelaySequences can do for the common case of
+// seqs being the very next contiguous run (what UnrelayedSequences
+// returns) is fall back to the ordinary BuildMessageProof, which produces
+// the next contiguous run up to limitSize bytes rather than exactly seqs.
+// To avoid silently relaying more than was asked for, that fallback is
+// rejected if it would cover sequences past the last one requested;
+// callers that need an exact subset must wait for a SeqMessageProofBuilder
+// receiver.
+func (l *Link) RelaySequences(seqs []int64) error {
+	if len(seqs) == 0 {
+		return nil
+	}
+
+	l.rmsMtx.Lock()
+	defer l.rmsMtx.Unlock()
+
+	var mp RelayMessageItem
+	var err error
+	if sb, ok := l.r.(SeqMessageProofBuilder); ok {
+		mp, err = sb.BuildMessageProofForSeqs(l.bls, seqs, l.limitSize)
+	} else if isContiguousFromRxSeq(l.bls, seqs) {
+		if mp, err = l.r.BuildMessageProof(l.bls, l.limitSize); err == nil && mp != nil {
+			if last := seqs[len(seqs)-1]; mp.LastSeqNum() > last {
+				return fmt.Errorf("receiver does not support RelaySequences: BuildMessageProof fallback would relay up to seq %d, past the requested %d; a seq-bounded receiver index is required to serve this request exactly", mp.LastSeqNum(), last)
+			}
+		}
+	} else {
+		return fmt.Errorf("receiver does not support RelaySequences for non-contiguous seqs")
+	}
+	if err != nil {
+		return err
+	}
+	if mp == nil {
+		return nil
+	}
+
+	rmi := make([]RelayMessageItem, 0, 2)
+	if h := l.r.GetHeightForSeq(seqs[0]); h > 0 {
+		bf, err := l.r.BuildBlockProof(l.bls, h)
+		if err != nil {
+			return err
+		}
+		if bf != nil {
+			rmi = append(rmi, bf)
+		}
+	}
+	rmi = append(rmi, mp)
+
+	m, err := l.r.BuildRelayMessage(rmi)
+	if err != nil {
+		return err
+	}
+
+	rm := &relayMessage{
+		id:       rand.Int(),
+		bls:      l.bls,
+		bpHeight: l.r.GetHeightForSeq(l.bls.RxSeq),
+		message:  m,
+		rmis:     rmi,
+	}
+	rm.builtAt = time.Now()
+	l.rms = append(l.rms, rm)
+	if err := l.journal.AppendRelayMessage(rm); err != nil {
+		l.l.Debugf("fail to append relay message id:%d to journal err:%+v", rm.id, err)
+	}
+
+	_, err = l.sendOne(rm)
+	return err
+}
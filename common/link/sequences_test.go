@@ -0,0 +1,78 @@
+package link
+
+import (
+	"testing"
+
+	"github.com/icon-project/btp2/common/types"
+)
+
+func TestIsContiguousFromRxSeq(t *testing.T) {
+	bls := &types.BMCLinkStatus{RxSeq: 10}
+
+	cases := []struct {
+		name string
+		seqs []int64
+		want bool
+	}{
+		{"contiguous run", []int64{11, 12, 13}, true},
+		{"single next seq", []int64{11}, true},
+		{"gap", []int64{11, 13}, false},
+		{"doesn't start at RxSeq+1", []int64{12, 13}, false},
+		{"out of order", []int64{12, 11}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isContiguousFromRxSeq(bls, c.seqs); got != c.want {
+				t.Fatalf("isContiguousFromRxSeq(%v) = %v, want %v", c.seqs, got, c.want)
+			}
+		})
+	}
+}
+
+func TestUnrelayedSequences(t *testing.T) {
+	l := &Link{
+		bls: &types.BMCLinkStatus{RxSeq: 2},
+		rss: []*receiveStatus{
+			{height: 1, seq: 3},
+			{height: 2, seq: 5},
+		},
+	}
+
+	seqs, err := l.UnrelayedSequences()
+	if err != nil {
+		t.Fatalf("UnrelayedSequences() err:%+v", err)
+	}
+	want := []int64{3, 4, 5}
+	if len(seqs) != len(want) {
+		t.Fatalf("UnrelayedSequences() = %v, want %v", seqs, want)
+	}
+	for i := range want {
+		if seqs[i] != want[i] {
+			t.Fatalf("UnrelayedSequences() = %v, want %v", seqs, want)
+		}
+	}
+}
+
+func TestUnrelayedSequencesNoneRemaining(t *testing.T) {
+	l := &Link{
+		bls: &types.BMCLinkStatus{RxSeq: 5},
+		rss: []*receiveStatus{
+			{height: 1, seq: 5},
+		},
+	}
+
+	seqs, err := l.UnrelayedSequences()
+	if err != nil {
+		t.Fatalf("UnrelayedSequences() err:%+v", err)
+	}
+	if len(seqs) != 0 {
+		t.Fatalf("expected no unrelayed sequences, got %v", seqs)
+	}
+}
+
+func TestRelaySequencesEmptyIsNoop(t *testing.T) {
+	l := &Link{bls: &types.BMCLinkStatus{RxSeq: 5}}
+	if err := l.RelaySequences(nil); err != nil {
+		t.Fatalf("RelaySequences(nil) err:%+v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package types
+
+// RelayTransport abstracts the wire protocol used to ship a RelayMessage to
+// the destination BMC gateway and to learn the outcome of previously sent
+// messages. Sender implementations hold a RelayTransport rather than
+// talking to the gateway directly, so new delivery protocols (gRPC
+// streaming, in addition to the existing JSON-RPC calls) can be added
+// without re-implementing the rest of Sender (status queries, size
+// limits, ...). Protocol-specific work such as signing, fragmentation and
+// step/fee handling is done by the transport that needs it, not by Sender.
+//
+// Implementations that multiplex messages over a single long-lived
+// connection (e.g. a bidirectional gRPC stream) may block inside Send when
+// the remote side applies flow control; Link.sendRelayMessage calls Send
+// synchronously, so that block is the back-pressure mechanism.
+type RelayTransport interface {
+	// Send ships rm.Bytes() to the destination and returns once the
+	// transport has accepted it for delivery. It does not wait for the
+	// gateway to execute it; the outcome is delivered later on Results().
+	Send(rm RelayMessage) error
+
+	// Results returns the channel on which RelayResult values are
+	// delivered as the destination reports them. Streaming transports
+	// deliver these asynchronously over the same connection used by
+	// Send; request/response transports deliver them as each poll
+	// completes.
+	Results() <-chan *RelayResult
+
+	// Close releases any resources held by the transport (connections,
+	// goroutines). Results() is closed after Close returns.
+	Close() error
+}